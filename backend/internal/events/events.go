@@ -0,0 +1,56 @@
+// Package events is the shared event-bus contract used by the jobs, design,
+// and notifications services in place of the old in-process ns.broadcast
+// channel. Events flow over Redis Streams so publishers and consumers don't
+// need to run in the same process.
+package events
+
+import (
+    "context"
+    "time"
+)
+
+// Event types published by JobService. Consumers should treat this list as
+// open-ended and ignore types they don't recognize.
+const (
+    TypeJobQueued    = "job.queued"
+    TypeJobStarted   = "job.started"
+    TypeJobCompleted = "job.completed"
+    TypeJobFailed    = "job.failed"
+
+    // TypeDesignUploaded is published by DesignService once a design has
+    // been persisted to the BlobStore and its row saved.
+    TypeDesignUploaded = "design.uploaded"
+)
+
+// Event is the wire format published and consumed on the bus. ID is the
+// Redis Streams entry ID once published (e.g. "1697051234567-0") and doubles
+// as the value a client passes back as Last-Event-ID on reconnect.
+type Event struct {
+    ID        string                 `json:"id,omitempty"`
+    Type      string                 `json:"type"`
+    TenantID  string                 `json:"tenant_id"`
+    Payload   map[string]interface{} `json:"payload,omitempty"`
+    Timestamp time.Time              `json:"timestamp"`
+}
+
+// Handler processes a single consumed event. Returning an error leaves the
+// event unacked in the consumer group's pending list; RedisBus.Subscribe
+// reads only new entries and does not re-read or claim pending ones.
+type Handler func(ctx context.Context, evt Event) error
+
+// Bus is the publish/subscribe contract backing services talk to. The only
+// implementation today is the Redis Streams one in redis.go, but handlers
+// and callers should depend on this interface rather than on Redis directly.
+type Bus interface {
+    // Publish appends evt to the bus and to the tenant's replay log,
+    // returning the assigned entry ID.
+    Publish(ctx context.Context, evt Event) (string, error)
+
+    // Subscribe runs handler for every event on the bus as part of
+    // consumer group, blocking until ctx is cancelled.
+    Subscribe(ctx context.Context, group, consumer string, handler Handler) error
+
+    // Replay returns events for tenantID persisted after afterID (exclusive).
+    // Pass an empty afterID to fetch from the start of the retained window.
+    Replay(ctx context.Context, tenantID, afterID string) ([]Event, error)
+}
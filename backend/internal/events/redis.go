@@ -0,0 +1,171 @@
+package events
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "time"
+
+    "github.com/go-redis/redis/v8"
+)
+
+// globalStream is the single stream every publisher writes to and every
+// consumer group reads from. Fan-out to individual tenants happens
+// in-process in the consumer (see NotificationService), not via per-tenant
+// stream subscriptions, so the bus doesn't need to scan tenant keys to
+// figure out who to read from.
+const globalStream = "events:stream"
+
+// tenantReplayStream is a capped per-tenant stream used only for replay on
+// reconnect; it is NOT consumed for live fan-out.
+func tenantReplayStream(tenantID string) string {
+    return fmt.Sprintf("events:tenant:%s:replay", tenantID)
+}
+
+// RedisBus implements Bus on top of Redis Streams, modeled on the
+// asynq-pubsub pattern: XADD to publish, XREADGROUP+XACK to consume.
+type RedisBus struct {
+    client  *redis.Client
+    replayN int64 // number of events retained per tenant for reconnect replay
+}
+
+// NewRedisBus constructs a RedisBus. replayN bounds how many past events per
+// tenant are kept for Last-Event-ID replay (older entries are trimmed).
+func NewRedisBus(client *redis.Client, replayN int64) *RedisBus {
+    if replayN <= 0 {
+        replayN = 200
+    }
+    return &RedisBus{client: client, replayN: replayN}
+}
+
+func (b *RedisBus) Publish(ctx context.Context, evt Event) (string, error) {
+    if evt.Timestamp.IsZero() {
+        evt.Timestamp = time.Now()
+    }
+
+    payload, err := json.Marshal(evt.Payload)
+    if err != nil {
+        return "", err
+    }
+
+    values := map[string]interface{}{
+        "type":      evt.Type,
+        "tenant_id": evt.TenantID,
+        "payload":   payload,
+        "timestamp": evt.Timestamp.UnixMilli(),
+    }
+
+    id, err := b.client.XAdd(ctx, &redis.XAddArgs{
+        Stream: globalStream,
+        Values: values,
+    }).Result()
+    if err != nil {
+        return "", err
+    }
+
+    // Best-effort replay copy; a failure here shouldn't fail the publish
+    // since the event already landed on the stream consumers read from.
+    // It's written under the same ID as the globalStream entry (rather
+    // than letting Redis assign its own) so a Last-Event-ID a client saw
+    // live on globalStream compares correctly against this replay stream.
+    b.client.XAdd(ctx, &redis.XAddArgs{
+        Stream: tenantReplayStream(evt.TenantID),
+        ID:     id,
+        MaxLen: b.replayN,
+        Approx: true,
+        Values: values,
+    })
+
+    return id, nil
+}
+
+func (b *RedisBus) Subscribe(ctx context.Context, group, consumer string, handler Handler) error {
+    err := b.client.XGroupCreateMkStream(ctx, globalStream, group, "0").Err()
+    if err != nil && !isBusyGroupErr(err) {
+        return err
+    }
+
+    for {
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        default:
+        }
+
+        streams, err := b.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+            Group:    group,
+            Consumer: consumer,
+            Streams:  []string{globalStream, ">"},
+            Count:    50,
+            Block:    5 * time.Second,
+        }).Result()
+        if err == redis.Nil {
+            continue
+        }
+        if err != nil {
+            if ctx.Err() != nil {
+                return ctx.Err()
+            }
+            continue
+        }
+
+        for _, stream := range streams {
+            for _, msg := range stream.Messages {
+                evt, parseErr := parseEvent(msg)
+                if parseErr == nil {
+                    if handleErr := handler(ctx, evt); handleErr != nil {
+                        continue // leave unacked in the pending list
+                    }
+                }
+                b.client.XAck(ctx, globalStream, group, msg.ID)
+            }
+        }
+    }
+}
+
+func (b *RedisBus) Replay(ctx context.Context, tenantID, afterID string) ([]Event, error) {
+    start := "-"
+    if afterID != "" {
+        start = "(" + afterID
+    }
+
+    msgs, err := b.client.XRange(ctx, tenantReplayStream(tenantID), start, "+").Result()
+    if err != nil {
+        return nil, err
+    }
+
+    events := make([]Event, 0, len(msgs))
+    for _, msg := range msgs {
+        evt, err := parseEvent(msg)
+        if err != nil {
+            continue
+        }
+        events = append(events, evt)
+    }
+    return events, nil
+}
+
+func parseEvent(msg redis.XMessage) (Event, error) {
+    evt := Event{ID: msg.ID}
+
+    if t, ok := msg.Values["type"].(string); ok {
+        evt.Type = t
+    }
+    if tid, ok := msg.Values["tenant_id"].(string); ok {
+        evt.TenantID = tid
+    }
+    if ts, ok := msg.Values["timestamp"].(string); ok {
+        var millis int64
+        fmt.Sscanf(ts, "%d", &millis)
+        evt.Timestamp = time.UnixMilli(millis)
+    }
+    if payload, ok := msg.Values["payload"].(string); ok && payload != "" {
+        _ = json.Unmarshal([]byte(payload), &evt.Payload)
+    }
+
+    return evt, nil
+}
+
+func isBusyGroupErr(err error) bool {
+    return err != nil && err.Error() == "BUSYGROUP Consumer Group name already exists"
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+    "sync"
+    "time"
+
+    "github.com/sony/gobreaker"
+)
+
+// Breaker tuning: trip once at least 10 requests have been seen in the
+// current 60s counting interval (counts are cleared at the end of each
+// interval) and 60% or more of them failed; stay open for 30s before
+// probing again with a single half-open request.
+const (
+    breakerFailureRatio  = 0.6
+    breakerMinRequests   = 10
+    breakerOpenTimeout   = 30 * time.Second
+    breakerCountInterval = 60 * time.Second
+)
+
+// BreakerRegistry holds one circuit breaker per upstream service so a
+// failing service can't drag down calls to a healthy one.
+type BreakerRegistry struct {
+    mu       sync.Mutex
+    breakers map[string]*gobreaker.CircuitBreaker
+}
+
+func NewBreakerRegistry() *BreakerRegistry {
+    return &BreakerRegistry{breakers: make(map[string]*gobreaker.CircuitBreaker)}
+}
+
+func (r *BreakerRegistry) For(serviceName string) *gobreaker.CircuitBreaker {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    if b, ok := r.breakers[serviceName]; ok {
+        return b
+    }
+
+    b := gobreaker.NewCircuitBreaker(gobreaker.Settings{
+        Name:        serviceName,
+        Interval:    breakerCountInterval,
+        Timeout:     breakerOpenTimeout,
+        MaxRequests: 1, // requests allowed through while half-open
+        ReadyToTrip: func(counts gobreaker.Counts) bool {
+            if counts.Requests < breakerMinRequests {
+                return false
+            }
+            failureRatio := float64(counts.TotalFailures) / float64(counts.Requests)
+            return failureRatio >= breakerFailureRatio
+        },
+    })
+
+    r.breakers[serviceName] = b
+    return b
+}
@@ -1,39 +1,56 @@
 package main
 
 import (
-    "context"
-    "log"
-    "net/http"
+    "fmt"
     "time"
-    
+
     "github.com/gin-gonic/gin"
     "github.com/redis/go-redis/v9"
 )
 
 type Gateway struct {
-    router     *gin.Engine
-    redisClient *redis.Client
-    services   map[string]string
+    router          *gin.Engine
+    redisClient     *redis.Client
+    services        map[string]string
+    tenantMiddleware *TenantMiddleware
+    rateLimiter     *RateLimiter
+    breakers        *BreakerRegistry
 }
 
 type TenantMiddleware struct {
     redis *redis.Client
+    cache *tenantCache
+}
+
+func NewTenantMiddleware(redisClient *redis.Client) *TenantMiddleware {
+    return &TenantMiddleware{
+        redis: redisClient,
+        cache: newTenantCache(10 * time.Second),
+    }
 }
 
 func (tm *TenantMiddleware) ExtractTenant() gin.HandlerFunc {
     return func(c *gin.Context) {
         host := c.Request.Host
         subdomain := extractSubdomain(host)
-        
+
+        if tenantID, ok := tm.cache.get(subdomain); ok {
+            c.Set("tenant_id", tenantID)
+            c.Set("subdomain", subdomain)
+            c.Next()
+            return
+        }
+
         // Validate tenant exists
-        tenantID, err := tm.redis.Get(c.Request.Context(), 
+        tenantID, err := tm.redis.Get(c.Request.Context(),
             fmt.Sprintf("tenant:%s", subdomain)).Result()
         if err != nil {
             c.JSON(404, gin.H{"error": "Tenant not found"})
             c.Abort()
             return
         }
-        
+
+        tm.cache.set(subdomain, tenantID)
         c.Set("tenant_id", tenantID)
         c.Set("subdomain", subdomain)
         c.Next()
@@ -43,7 +60,9 @@ func (tm *TenantMiddleware) ExtractTenant() gin.HandlerFunc {
 func (g *Gateway) setupRoutes() {
     v1 := g.router.Group("/api/v1")
     v1.Use(g.tenantMiddleware.ExtractTenant())
-    
+    v1.Use(g.rateLimiter.Middleware())
+    v1.Use(tracingMiddleware())
+
     // Route to microservices
     v1.Any("/auth/*path", g.proxyToService("auth-service"))
     v1.Any("/etsy/*path", g.proxyToService("etsy-service"))
@@ -0,0 +1,149 @@
+package main
+
+import (
+    "bytes"
+    "fmt"
+    "io"
+    "log"
+    "math/rand"
+    "net/http"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "github.com/sony/gobreaker"
+)
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// idempotentMethods are safe to retry without risking a duplicate
+// side-effecting call downstream.
+var idempotentMethods = map[string]bool{
+    http.MethodGet:     true,
+    http.MethodHead:    true,
+    http.MethodOptions: true,
+    http.MethodPut:     true,
+    http.MethodDelete:  true,
+}
+
+const (
+    maxProxyAttempts = 3
+    proxyBaseBackoff = 50 * time.Millisecond
+)
+
+// proxyToService forwards the request to the named upstream service behind
+// a circuit breaker, retrying idempotent requests with jittered backoff on
+// transient failures.
+func (g *Gateway) proxyToService(serviceName string) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        baseURL, ok := g.services[serviceName]
+        if !ok {
+            c.JSON(502, gin.H{"error": "Unknown upstream service"})
+            return
+        }
+
+        body, err := io.ReadAll(c.Request.Body)
+        if err != nil {
+            c.JSON(400, gin.H{"error": "Failed to read request body"})
+            return
+        }
+
+        breaker := g.breakers.For(serviceName)
+        attempts := 1
+        if idempotentMethods[c.Request.Method] {
+            attempts = maxProxyAttempts
+        }
+
+        var resp *http.Response
+        var lastErr error
+
+        for attempt := 0; attempt < attempts; attempt++ {
+            if attempt > 0 {
+                time.Sleep(proxyBackoff(attempt))
+            }
+
+            result, err := breaker.Execute(func() (interface{}, error) {
+                return g.doProxyRequest(c, baseURL, body)
+            })
+            // doProxyRequest returns the response alongside a non-nil error
+            // for upstream 5xx statuses (see its doc comment), so gobreaker
+            // still records the call as a failure even though a response
+            // came back; keep it so we can relay the real status/body if
+            // this turns out to be our last attempt. An earlier attempt's
+            // response is never relayed once a later one replaces it here,
+            // so close its body now or the underlying connection leaks on
+            // every retried 5xx.
+            if r, ok := result.(*http.Response); ok && r != nil {
+                if resp != nil {
+                    resp.Body.Close()
+                }
+                resp = r
+            }
+
+            if err == nil {
+                lastErr = nil
+                break
+            }
+
+            lastErr = err
+            if err == gobreaker.ErrOpenState || err == gobreaker.ErrTooManyRequests {
+                break // upstream is known bad; don't burn retries on it
+            }
+        }
+
+        if resp == nil {
+            log.Printf("proxy to %s failed: %v", serviceName, lastErr)
+            c.JSON(502, gin.H{"error": "Upstream service unavailable"})
+            return
+        }
+        if lastErr != nil {
+            log.Printf("proxy to %s exhausted retries, relaying last upstream response: %v", serviceName, lastErr)
+        }
+        defer resp.Body.Close()
+
+        for key, values := range resp.Header {
+            for _, v := range values {
+                c.Writer.Header().Add(key, v)
+            }
+        }
+        c.Status(resp.StatusCode)
+        io.Copy(c.Writer, resp.Body)
+    }
+}
+
+// doProxyRequest issues the upstream request. http.Client.Do only errors on
+// transport-level failures, so a reachable-but-unhealthy upstream (502/503/
+// any 5xx) would otherwise look like a success to both the circuit breaker
+// and the retry loop above. Treat 5xx as a failure too, returning the
+// response alongside the error so the caller can still relay it if retries
+// are exhausted.
+func (g *Gateway) doProxyRequest(c *gin.Context, baseURL string, body []byte) (*http.Response, error) {
+    req, err := http.NewRequestWithContext(c.Request.Context(), c.Request.Method, baseURL+c.Request.URL.Path, bytes.NewReader(body))
+    if err != nil {
+        return nil, err
+    }
+    req.Header = c.Request.Header.Clone()
+    req.URL.RawQuery = c.Request.URL.RawQuery
+
+    if traceparent, ok := c.Get(traceparentHeader); ok {
+        req.Header.Set(traceparentHeader, traceparent.(string))
+    }
+    req.Header.Set("X-Tenant-ID", c.GetString("tenant_id"))
+
+    resp, err := httpClient.Do(req)
+    if err != nil {
+        return nil, err
+    }
+    if resp.StatusCode >= http.StatusInternalServerError {
+        return resp, fmt.Errorf("upstream %s returned %d", baseURL, resp.StatusCode)
+    }
+    return resp, nil
+}
+
+// proxyBackoff returns a jittered exponential delay for retry attempt n
+// (1-indexed), capped well below request timeouts since these are edge
+// retries, not background job retries.
+func proxyBackoff(attempt int) time.Duration {
+    d := proxyBaseBackoff << uint(attempt-1)
+    jitter := time.Duration(rand.Int63n(int64(d)))
+    return d + jitter
+}
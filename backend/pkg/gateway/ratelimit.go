@@ -0,0 +1,71 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "github.com/redis/go-redis/v9"
+)
+
+// defaultRateLimit is used for tenants whose record doesn't set one.
+const defaultRateLimit = 120 // requests per window
+
+const rateLimitWindow = time.Minute
+
+// RateLimiter enforces a per-tenant token-bucket-ish limit using a plain
+// Redis counter: INCR the tenant's window key, EXPIRE it on first use, and
+// reject once the tenant's configured limit is exceeded. Simpler than a
+// true token bucket: it enforces a fixed-window limit, at the cost of an
+// INCR plus a lookup of the tenant's limit on every request.
+type RateLimiter struct {
+    redis *redis.Client
+}
+
+func NewRateLimiter(redisClient *redis.Client) *RateLimiter {
+    return &RateLimiter{redis: redisClient}
+}
+
+func rateLimitKey(tenantID string) string {
+    window := time.Now().Truncate(rateLimitWindow).Unix()
+    return fmt.Sprintf("ratelimit:%s:%d", tenantID, window)
+}
+
+// limitFor loads the tenant's configured requests-per-window limit, falling
+// back to defaultRateLimit if the tenant record doesn't set one.
+func (rl *RateLimiter) limitFor(ctx context.Context, tenantID string) int {
+    val, err := rl.redis.Get(ctx, fmt.Sprintf("tenant:%s:rate_limit", tenantID)).Int()
+    if err != nil {
+        return defaultRateLimit
+    }
+    return val
+}
+
+func (rl *RateLimiter) Middleware() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        tenantID := c.GetString("tenant_id")
+        ctx := c.Request.Context()
+
+        key := rateLimitKey(tenantID)
+        count, err := rl.redis.Incr(ctx, key).Result()
+        if err != nil {
+            // Fail open: a Redis blip shouldn't take down the whole edge.
+            c.Next()
+            return
+        }
+        if count == 1 {
+            rl.redis.Expire(ctx, key, rateLimitWindow)
+        }
+
+        limit := rl.limitFor(ctx, tenantID)
+        if int(count) > limit {
+            c.Header("Retry-After", fmt.Sprintf("%d", int(rateLimitWindow.Seconds())))
+            c.JSON(429, gin.H{"error": "Rate limit exceeded"})
+            c.Abort()
+            return
+        }
+
+        c.Next()
+    }
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+    "sync"
+    "time"
+)
+
+// tenantCache is a short-TTL in-process cache of subdomain -> tenant_id
+// lookups, so ExtractTenant doesn't round-trip to Redis on every request.
+type tenantCache struct {
+    ttl time.Duration
+    mu  sync.RWMutex
+    m   map[string]tenantCacheEntry
+}
+
+type tenantCacheEntry struct {
+    tenantID  string
+    expiresAt time.Time
+}
+
+func newTenantCache(ttl time.Duration) *tenantCache {
+    return &tenantCache{
+        ttl: ttl,
+        m:   make(map[string]tenantCacheEntry),
+    }
+}
+
+func (c *tenantCache) get(subdomain string) (string, bool) {
+    c.mu.RLock()
+    entry, ok := c.m[subdomain]
+    c.mu.RUnlock()
+    if !ok || time.Now().After(entry.expiresAt) {
+        return "", false
+    }
+    return entry.tenantID, true
+}
+
+func (c *tenantCache) set(subdomain, tenantID string) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    c.m[subdomain] = tenantCacheEntry{
+        tenantID:  tenantID,
+        expiresAt: time.Now().Add(c.ttl),
+    }
+}
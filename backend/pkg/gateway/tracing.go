@@ -0,0 +1,39 @@
+package main
+
+import (
+    "crypto/rand"
+    "encoding/hex"
+    "fmt"
+
+    "github.com/gin-gonic/gin"
+)
+
+const traceparentHeader = "traceparent"
+
+// traceparent follows the W3C format: version-traceid-parentid-flags.
+// See https://www.w3.org/TR/trace-context/#traceparent-header.
+func newTraceparent() string {
+    traceID := randomHex(16)
+    spanID := randomHex(8)
+    return fmt.Sprintf("00-%s-%s-01", traceID, spanID)
+}
+
+func randomHex(n int) string {
+    b := make([]byte, n)
+    _, _ = rand.Read(b)
+    return hex.EncodeToString(b)
+}
+
+// tracingMiddleware assigns a traceparent to requests that don't already
+// carry one from upstream, and stashes it in the context so proxyToService
+// can propagate the same one to the downstream service.
+func tracingMiddleware() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        traceparent := c.GetHeader(traceparentHeader)
+        if traceparent == "" {
+            traceparent = newTraceparent()
+        }
+        c.Set(traceparentHeader, traceparent)
+        c.Next()
+    }
+}
@@ -0,0 +1,150 @@
+// services/design/blobstore.go
+package main
+
+import (
+    "context"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "strings"
+    "time"
+
+    "github.com/minio/minio-go/v7"
+    "github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// BlobStore abstracts the object-storage backend so MinIO, S3, and a
+// local-disk backend for dev can all satisfy DesignService's storage needs.
+// GCS is not implemented yet; see ErrGCSNotImplemented.
+type BlobStore interface {
+    // Put uploads size bytes from r to key in bucket.
+    Put(ctx context.Context, bucket, key string, r io.Reader, size int64, contentType string) error
+    // PresignedGet returns a time-limited URL for downloading key from bucket.
+    PresignedGet(ctx context.Context, bucket, key string, expiry time.Duration) (string, error)
+    // Get opens key from bucket for streaming reads, e.g. to serve thumbnails.
+    Get(ctx context.Context, bucket, key string) (io.ReadCloser, error)
+}
+
+// StorageConfig selects and configures the active BlobStore backend.
+type StorageConfig struct {
+    Backend       string // "minio", "s3", or "local"; see ErrGCSNotImplemented for "gcs"
+    Endpoint      string
+    AccessKey     string
+    SecretKey     string
+    Region        string
+    UseSSL        bool
+    LocalBasePath string // used by the "local" backend only
+}
+
+// ErrGCSNotImplemented is returned by NewBlobStore for Backend: "gcs". It's
+// called out as its own error (rather than falling into the generic
+// "unknown backend" case below) because gcs is a recognized, planned
+// backend name, not a typo — callers should fail startup on it, not mistake
+// it for a config mistake.
+var ErrGCSNotImplemented = fmt.Errorf("blobstore: gcs backend not yet implemented")
+
+// NewBlobStore constructs the BlobStore configured by cfg.
+func NewBlobStore(cfg StorageConfig) (BlobStore, error) {
+    switch cfg.Backend {
+    case "minio", "s3":
+        client, err := minio.New(cfg.Endpoint, &minio.Options{
+            Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+            Secure: cfg.UseSSL,
+            Region: cfg.Region,
+        })
+        if err != nil {
+            return nil, fmt.Errorf("blobstore: failed to init %s client: %w", cfg.Backend, err)
+        }
+        return &minioBlobStore{client: client}, nil
+    case "gcs":
+        return nil, ErrGCSNotImplemented
+    case "local", "":
+        base := cfg.LocalBasePath
+        if base == "" {
+            base = "./data/designs"
+        }
+        return &localBlobStore{basePath: base}, nil
+    default:
+        return nil, fmt.Errorf("blobstore: unknown backend %q", cfg.Backend)
+    }
+}
+
+// minioBlobStore backs BlobStore with MinIO (also used for S3, which speaks
+// the same API).
+type minioBlobStore struct {
+    client *minio.Client
+}
+
+func (m *minioBlobStore) Put(ctx context.Context, bucket, key string, r io.Reader, size int64, contentType string) error {
+    _, err := m.client.PutObject(ctx, bucket, key, r, size, minio.PutObjectOptions{
+        ContentType: contentType,
+    })
+    return err
+}
+
+func (m *minioBlobStore) PresignedGet(ctx context.Context, bucket, key string, expiry time.Duration) (string, error) {
+    u, err := m.client.PresignedGetObject(ctx, bucket, key, expiry, nil)
+    if err != nil {
+        return "", err
+    }
+    return u.String(), nil
+}
+
+func (m *minioBlobStore) Get(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
+    return m.client.GetObject(ctx, bucket, key, minio.GetObjectOptions{})
+}
+
+// localBlobStore stores blobs on local disk under basePath/bucket/key, for
+// development without a running MinIO/S3 instance.
+type localBlobStore struct {
+    basePath string
+}
+
+// pathFor resolves bucket/key under basePath and, as defense in depth against
+// a key that smuggled ".." past its caller's own sanitization, verifies the
+// resolved path didn't escape basePath.
+func (l *localBlobStore) pathFor(bucket, key string) (string, error) {
+    base := filepath.Clean(l.basePath)
+    dest := filepath.Clean(filepath.Join(base, bucket, filepath.FromSlash(key)))
+    if dest != base && !strings.HasPrefix(dest, base+string(filepath.Separator)) {
+        return "", fmt.Errorf("blobstore: key %q escapes base path", key)
+    }
+    return dest, nil
+}
+
+func (l *localBlobStore) Put(ctx context.Context, bucket, key string, r io.Reader, size int64, contentType string) error {
+    dest, err := l.pathFor(bucket, key)
+    if err != nil {
+        return err
+    }
+    if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+        return err
+    }
+    f, err := os.Create(dest)
+    if err != nil {
+        return err
+    }
+    defer f.Close()
+
+    _, err = io.Copy(f, r)
+    return err
+}
+
+func (l *localBlobStore) PresignedGet(ctx context.Context, bucket, key string, expiry time.Duration) (string, error) {
+    dest, err := l.pathFor(bucket, key)
+    if err != nil {
+        return "", err
+    }
+    // No real signing for local dev; the path is returned as-is behind the
+    // service's own /designs/:id/download handler.
+    return fmt.Sprintf("file://%s", dest), nil
+}
+
+func (l *localBlobStore) Get(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
+    dest, err := l.pathFor(bucket, key)
+    if err != nil {
+        return nil, err
+    }
+    return os.Open(dest)
+}
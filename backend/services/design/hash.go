@@ -0,0 +1,78 @@
+// services/design/hash.go
+package main
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "image"
+    _ "image/gif"
+    _ "image/jpeg"
+    _ "image/png"
+    "io"
+    "os"
+
+    "github.com/corona10/goimagehash"
+)
+
+// hashedUpload bundles the content hashes computed while buffering an
+// upload, plus a reader positioned at the start so the caller can still
+// stream the bytes on to the BlobStore. Call Close once the caller is done
+// reading to release the underlying temp file.
+type hashedUpload struct {
+    reader *os.File
+    sha256 string
+    // pHash is a perceptual hash ("" if the upload isn't a decodable image,
+    // e.g. a vector file), used to flag near-duplicate designs that a
+    // byte-identical SHA-256 check would miss.
+    pHash string
+}
+
+func (h *hashedUpload) Close() error {
+    return h.reader.Close()
+}
+
+// hashUpload buffers r to a temp file while computing its SHA-256, then
+// attempts to decode it as an image for a perceptual hash. It returns the
+// temp file itself, seeked back to the start, as the reader the caller
+// streams on to the BlobStore — the upload is buffered to disk exactly
+// once, rather than also being read into memory in full.
+func hashUpload(r io.Reader) (*hashedUpload, error) {
+    tmp, err := os.CreateTemp("", "design-upload-*")
+    if err != nil {
+        return nil, err
+    }
+    // Unlinking now (instead of on Close) is safe on POSIX: the open fd
+    // keeps the data readable until Close, at which point the kernel
+    // reclaims the space immediately rather than leaving an orphaned file
+    // if the process dies before a deferred os.Remove would run.
+    os.Remove(tmp.Name())
+
+    hasher := sha256.New()
+    if _, err := io.Copy(tmp, io.TeeReader(r, hasher)); err != nil {
+        tmp.Close()
+        return nil, err
+    }
+
+    if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+        tmp.Close()
+        return nil, err
+    }
+
+    pHash := ""
+    if img, _, err := image.Decode(tmp); err == nil {
+        if h, err := goimagehash.PerceptionHash(img); err == nil {
+            pHash = h.ToString()
+        }
+    }
+
+    if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+        tmp.Close()
+        return nil, err
+    }
+
+    return &hashedUpload{
+        reader: tmp,
+        sha256: hex.EncodeToString(hasher.Sum(nil)),
+        pHash:  pHash,
+    }, nil
+}
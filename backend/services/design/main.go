@@ -3,108 +3,325 @@ package main
 
 import (
     "context"
+    "encoding/json"
     "fmt"
-    "io"
     "path/filepath"
-    
+    "time"
+
     "github.com/gin-gonic/gin"
-    "github.com/minio/minio-go/v7"
+    "github.com/go-redis/redis/v8"
+    "github.com/google/uuid"
     "gorm.io/gorm"
+
+    "github.com/NandoSerrano85/Printer-Saas/backend/internal/events"
+)
+
+// Bucket names. Thumbnails live in a separate bucket so they can have their
+// own lifecycle/CDN caching rules independent of source designs.
+const (
+    designsBucket    = "tenant-designs"
+    thumbnailsBucket = "tenant-design-thumbnails"
 )
 
+const downloadURLExpiry = 15 * time.Minute
+
+// renderQueue must match services/renderer's renderQueue constant: the two
+// are independent `package main` binaries and can't share the value via
+// import, so it's duplicated here deliberately.
+const renderQueue = "jobs:render"
+
+// validRecipes mirrors the whitelist loaded from services/renderer/recipes/
+// at startup. Kept in sync by hand for the same reason renderQueue is
+// duplicated above; a recipe added there needs to be added here too.
+var validRecipes = map[string]bool{
+    "t-shirt": true,
+    "mug":     true,
+    "poster":  true,
+}
+
 type DesignService struct {
-    db          *gorm.DB
-    minioClient *minio.Client
-    router      *gin.Engine
+    db        *gorm.DB
+    blobStore BlobStore
+    redis     *redis.Client
+    router    *gin.Engine
+    eventBus  events.Bus
+}
+
+// MockupGenerationJob is the payload queued onto renderQueue for
+// services/renderer to consume; its shape must match renderer's
+// MockupGenerationJob (services/renderer/main.go) field-for-field.
+type MockupGenerationJob struct {
+    JobID    string `json:"job_id"`
+    TenantID string `json:"tenant_id"`
+    DesignID string `json:"design_id"`
+    FilePath string `json:"file_path"`
+    Recipe   string `json:"recipe"`
+}
+
+// renderJobType is the Type services/jobs records for mockup jobs; surfaced
+// through the normal job status/list endpoints alongside jobs queued by
+// services/jobs itself.
+const renderJobType = "render_mockup"
+
+// renderJobMaxRetry mirrors services/jobs.defaultMaxRetry; the renderer's
+// failures go through the same retry/DLQ machinery as any other job type.
+const renderJobMaxRetry = 5
+
+// renderJob mirrors services/jobs.Job's wire shape and Redis key scheme
+// (job:<id> hash, tenant:<id>:jobs ZSET index) closely enough that
+// JobService's getJobStatus/listTenantJobs/retryJob/completeJobCallback can
+// see and update a mockup job exactly like one it queued itself. The two
+// services are independent `package main` binaries and can't share the
+// type via import, hence the duplication; only the fields DesignService
+// itself needs to set are included here, the rest round-trip through
+// JobService untouched.
+type renderJob struct {
+    ID        string                 `json:"id"`
+    TenantID  string                 `json:"tenant_id"`
+    Type      string                 `json:"type"`
+    Status    string                 `json:"status"`
+    Payload   map[string]interface{} `json:"payload"`
+    Priority  string                 `json:"priority,omitempty"`
+    MaxRetry  int                    `json:"max_retry"`
+    CreatedAt time.Time              `json:"created_at"`
+}
+
+func jobKey(jobID string) string {
+    return fmt.Sprintf("job:%s", jobID)
+}
+
+func tenantJobsKey(tenantID string) string {
+    return fmt.Sprintf("tenant:%s:jobs", tenantID)
+}
+
+// saveRenderJob writes job's hash and tenant index entry using the same
+// scheme as services/jobs.JobService.saveJob, so the render job is visible
+// to every endpoint that reads job:<id> or tenant:<id>:jobs.
+func (ds *DesignService) saveRenderJob(ctx context.Context, job renderJob) error {
+    jobData, err := json.Marshal(job)
+    if err != nil {
+        return err
+    }
+
+    pipe := ds.redis.TxPipeline()
+    pipe.Set(ctx, jobKey(job.ID), jobData, 24*time.Hour)
+    pipe.ZAdd(ctx, tenantJobsKey(job.TenantID), &redis.Z{
+        Score:  float64(job.CreatedAt.Unix()),
+        Member: job.ID,
+    })
+    _, err = pipe.Exec(ctx)
+    return err
 }
 
 type Design struct {
-    ID          uint   `gorm:"primaryKey"`
-    TenantID    string `gorm:"index"`
-    Name        string
-    FilePath    string
-    FileSize    int64
-    ContentType string
-    Tags        []string `gorm:"serializer:json"`
-    CreatedAt   time.Time
+    ID            uint   `gorm:"primaryKey"`
+    TenantID      string `gorm:"index"`
+    Name          string
+    FilePath      string
+    ThumbnailPath string
+    FileSize      int64
+    ContentType   string
+    SHA256        string `gorm:"index"`
+    PHash         string
+    Tags          []string `gorm:"serializer:json"`
+    CreatedAt     time.Time
 }
 
 func (ds *DesignService) uploadDesign(c *gin.Context) {
     tenantID := c.GetString("tenant_id")
-    
+
     file, header, err := c.Request.FormFile("design")
     if err != nil {
         c.JSON(400, gin.H{"error": "Invalid file"})
         return
     }
     defer file.Close()
-    
-    // Generate tenant-scoped file path
-    fileName := fmt.Sprintf("%s/%s/%s", 
-        tenantID, "designs", header.Filename)
-    
-    // Upload to MinIO
-    _, err = ds.minioClient.PutObject(
-        context.Background(),
-        "tenant-designs",
-        fileName,
-        file,
-        header.Size,
-        minio.PutObjectOptions{
-            ContentType: header.Header.Get("Content-Type"),
-        },
-    )
-    
+
+    hashed, err := hashUpload(file)
     if err != nil {
+        c.JSON(400, gin.H{"error": "Failed to read upload"})
+        return
+    }
+    defer hashed.Close()
+
+    // Duplicate uploads (same tenant, same content hash) short-circuit
+    // rather than re-uploading and re-storing identical bytes.
+    var existing Design
+    if result := ds.db.Where("tenant_id = ? AND sha256 = ?", tenantID, hashed.sha256).First(&existing); result.Error == nil {
+        c.JSON(200, existing)
+        return
+    }
+
+    // header.Filename is attacker-controlled; strip any directory component
+    // so it can't escape the tenant-scoped prefix below via "../" segments.
+    safeName := filepath.Base(header.Filename)
+    if safeName == "." || safeName == ".." || safeName == "" {
+        c.JSON(400, gin.H{"error": "Invalid file name"})
+        return
+    }
+
+    // Generate tenant-scoped file path
+    fileName := fmt.Sprintf("%s/%s/%s", tenantID, "designs", safeName)
+
+    ctx := c.Request.Context()
+    if err := ds.blobStore.Put(ctx, designsBucket, fileName, hashed.reader, header.Size, header.Header.Get("Content-Type")); err != nil {
         c.JSON(500, gin.H{"error": "Upload failed"})
         return
     }
-    
-    // Save metadata to database
+
     design := Design{
         TenantID:    tenantID,
         Name:        header.Filename,
         FilePath:    fileName,
         FileSize:    header.Size,
         ContentType: header.Header.Get("Content-Type"),
+        SHA256:      hashed.sha256,
+        PHash:       hashed.pHash,
     }
-    
+
     ds.db.Create(&design)
-    
+
+    if ds.eventBus != nil {
+        ds.eventBus.Publish(ctx, events.Event{
+            Type:     events.TypeDesignUploaded,
+            TenantID: tenantID,
+            Payload: map[string]interface{}{
+                "design_id": design.ID,
+                "name":      design.Name,
+            },
+        })
+    }
+
     c.JSON(201, design)
 }
 
+// downloadDesign returns a time-limited pre-signed URL scoped to the
+// design's own object key, so one tenant can never obtain a URL for
+// another tenant's blob even if they guess the key.
+func (ds *DesignService) downloadDesign(c *gin.Context) {
+    tenantID := c.GetString("tenant_id")
+    designID := c.Param("id")
+
+    var design Design
+    if result := ds.db.Where("tenant_id = ? AND id = ?", tenantID, designID).First(&design); result.Error != nil {
+        c.JSON(404, gin.H{"error": "Design not found"})
+        return
+    }
+
+    url, err := ds.blobStore.PresignedGet(c.Request.Context(), designsBucket, design.FilePath, downloadURLExpiry)
+    if err != nil {
+        c.JSON(500, gin.H{"error": "Failed to generate download URL"})
+        return
+    }
+
+    c.JSON(200, gin.H{"url": url, "expires_in": int(downloadURLExpiry.Seconds())})
+}
+
+// thumbnailDesign streams the design's thumbnail from the thumbnails
+// bucket, and 404s while the design row has no ThumbnailPath. Nothing in
+// this service sets ThumbnailPath, and services/renderer uploads its output
+// to its own artifacts bucket rather than to thumbnailsBucket.
+func (ds *DesignService) thumbnailDesign(c *gin.Context) {
+    tenantID := c.GetString("tenant_id")
+    designID := c.Param("id")
+
+    var design Design
+    if result := ds.db.Where("tenant_id = ? AND id = ?", tenantID, designID).First(&design); result.Error != nil {
+        c.JSON(404, gin.H{"error": "Design not found"})
+        return
+    }
+
+    if design.ThumbnailPath == "" {
+        c.JSON(404, gin.H{"error": "Thumbnail not generated yet"})
+        return
+    }
+
+    obj, err := ds.blobStore.Get(c.Request.Context(), thumbnailsBucket, design.ThumbnailPath)
+    if err != nil {
+        c.JSON(404, gin.H{"error": "Thumbnail not found"})
+        return
+    }
+    defer obj.Close()
+
+    c.DataFromReader(200, -1, "image/jpeg", obj, nil)
+}
+
 func (ds *DesignService) generateMockup(c *gin.Context) {
     tenantID := c.GetString("tenant_id")
     designID := c.Param("id")
-    
+
     // Get design from database
     var design Design
-    result := ds.db.Where("tenant_id = ? AND id = ?", 
+    result := ds.db.Where("tenant_id = ? AND id = ?",
         tenantID, designID).First(&design)
-    
+
     if result.Error != nil {
         c.JSON(404, gin.H{"error": "Design not found"})
         return
     }
-    
+
+    var mockupRequest struct {
+        Recipe string `json:"recipe" binding:"required"`
+    }
+    if err := c.ShouldBindJSON(&mockupRequest); err != nil {
+        c.JSON(400, gin.H{"error": err.Error()})
+        return
+    }
+    if !validRecipes[mockupRequest.Recipe] {
+        c.JSON(400, gin.H{"error": fmt.Sprintf("unknown recipe %q", mockupRequest.Recipe)})
+        return
+    }
+
     // Queue mockup generation
     job := MockupGenerationJob{
+        JobID:    uuid.NewString(),
         TenantID: tenantID,
         DesignID: designID,
         FilePath: design.FilePath,
+        Recipe:   mockupRequest.Recipe,
     }
-    
-    // Submit to job queue (Redis/RQ)
-    jobID, err := ds.queueMockupJob(job)
-    if err != nil {
+
+    ctx := c.Request.Context()
+
+    // Record a job:<id> entry before handing off to services/renderer, so
+    // completeJobCallback has something to load, and getJobStatus/
+    // listTenantJobs/retryJob see this like any other job. Without this the
+    // renderer's callback 404s against an ID nothing ever saved.
+    rJob := renderJob{
+        ID:       job.JobID,
+        TenantID: tenantID,
+        Type:     renderJobType,
+        Status:   "queued",
+        Payload: map[string]interface{}{
+            "design_id": designID,
+            "file_path": design.FilePath,
+            "recipe":    mockupRequest.Recipe,
+        },
+        MaxRetry:  renderJobMaxRetry,
+        CreatedAt: time.Now(),
+    }
+    if err := ds.saveRenderJob(ctx, rJob); err != nil {
+        c.JSON(500, gin.H{"error": "Failed to store job"})
+        return
+    }
+
+    // Submit to services/renderer via renderQueue
+    if err := ds.queueMockupJob(ctx, job); err != nil {
         c.JSON(500, gin.H{"error": "Failed to queue job"})
         return
     }
-    
+
     c.JSON(202, gin.H{
-        "job_id": jobID,
-        "status": "queued",
-        "message": "Mockup generation started"
+        "job_id":  job.JobID,
+        "status":  "queued",
+        "message": "Mockup generation started",
     })
+}
+
+func (ds *DesignService) queueMockupJob(ctx context.Context, job MockupGenerationJob) error {
+    payload, err := json.Marshal(job)
+    if err != nil {
+        return err
+    }
+    return ds.redis.LPush(ctx, renderQueue, payload).Err()
 }
\ No newline at end of file
@@ -2,17 +2,62 @@
 package main
 
 import (
+    "context"
     "encoding/json"
+    "fmt"
     "net/http"
+    "strconv"
     "time"
-    
+
     "github.com/gin-gonic/gin"
     "github.com/go-redis/redis/v8"
+
+    "github.com/NandoSerrano85/Printer-Saas/backend/internal/events"
 )
 
 type JobService struct {
-    redis  *redis.Client
-    router *gin.Engine
+    redis    *redis.Client
+    router   *gin.Engine
+    eventBus events.Bus
+}
+
+// publishEvent is a no-op when eventBus isn't configured, so JobService
+// still works in tests/contexts that don't wire an event bus.
+func (js *JobService) publishEvent(ctx context.Context, eventType string, job *Job) {
+    if js.eventBus == nil {
+        return
+    }
+    js.eventBus.Publish(ctx, events.Event{
+        Type:     eventType,
+        TenantID: job.TenantID,
+        Payload: map[string]interface{}{
+            "job_id": job.ID,
+            "type":   job.Type,
+            "status": job.Status,
+        },
+    })
+}
+
+// Job statuses. Terminal states are "completed" and "dead_letter"; "failed"
+// marks a job waiting on its scheduled retry (see retry.go), and
+// "cancelled" is terminal but only reachable from "queued".
+const (
+    JobStatusQueued     = "queued"
+    JobStatusRunning    = "running"
+    JobStatusCompleted  = "completed"
+    JobStatusFailed     = "failed"
+    JobStatusDeadLetter = "dead_letter"
+    JobStatusCancelled  = "cancelled"
+)
+
+// tenantJobsKey is the ZSET index used by listTenantJobs, scored by CreatedAt
+// unix time so callers can paginate with ZRANGEBYSCORE instead of KEYS.
+func tenantJobsKey(tenantID string) string {
+    return fmt.Sprintf("tenant:%s:jobs", tenantID)
+}
+
+func jobKey(jobID string) string {
+    return fmt.Sprintf("job:%s", jobID)
 }
 
 type Job struct {
@@ -22,9 +67,41 @@ type Job struct {
     Status      string                 `json:"status"`
     Payload     map[string]interface{} `json:"payload"`
     Result      map[string]interface{} `json:"result,omitempty"`
+    Priority    string                 `json:"priority,omitempty"`
+    RetryCount  int                    `json:"retry_count"`
+    MaxRetry    int                    `json:"max_retry"`
+    RetriedAt   *time.Time             `json:"retried_at,omitempty"`
     CreatedAt   time.Time              `json:"created_at"`
     CompletedAt *time.Time             `json:"completed_at,omitempty"`
     Error       string                 `json:"error,omitempty"`
+
+    // LeaseExpiresAt is set when a worker picks up a job (Status becomes
+    // Running) and refreshed periodically for as long as the handler is
+    // still executing. Janitor.reclaim only re-queues a Running job once
+    // this has actually passed, rather than reclaiming every Running job on
+    // every tick regardless of whether its worker is still alive.
+    LeaseExpiresAt *time.Time `json:"lease_expires_at,omitempty"`
+}
+
+// defaultMaxRetry is used when a job request doesn't specify one.
+const defaultMaxRetry = 5
+
+// saveJob persists the job hash and keeps the per-tenant ZSET index in sync
+// so listTenantJobs can paginate without scanning the whole keyspace.
+func (js *JobService) saveJob(ctx context.Context, job *Job) error {
+    jobData, err := json.Marshal(job)
+    if err != nil {
+        return err
+    }
+
+    pipe := js.redis.TxPipeline()
+    pipe.Set(ctx, jobKey(job.ID), jobData, 24*time.Hour)
+    pipe.ZAdd(ctx, tenantJobsKey(job.TenantID), &redis.Z{
+        Score:  float64(job.CreatedAt.Unix()),
+        Member: job.ID,
+    })
+    _, err = pipe.Exec(ctx)
+    return err
 }
 
 func (js *JobService) queueJob(c *gin.Context) {
@@ -43,101 +120,271 @@ func (js *JobService) queueJob(c *gin.Context) {
     
     // Generate job ID
     jobID := generateJobID()
-    
+
     job := Job{
         ID:        jobID,
         TenantID:  tenantID,
         Type:      jobRequest.Type,
-        Status:    "queued",
+        Status:    JobStatusQueued,
         Payload:   jobRequest.Payload,
+        Priority:  jobRequest.Priority,
+        MaxRetry:  defaultMaxRetry,
         CreatedAt: time.Now(),
     }
-    
-    // Store job metadata
-    jobData, _ := json.Marshal(job)
-    js.redis.Set(c.Request.Context(), 
-        fmt.Sprintf("job:%s", jobID), jobData, 24*time.Hour)
-    
-    // Queue job based on type and priority
-    queueName := js.getQueueForJob(jobRequest.Type, jobRequest.Priority)
-    
-    jobPayload := map[string]interface{}{
-        "job_id":    jobID,
-        "tenant_id": tenantID,
-        "type":      jobRequest.Type,
-        "payload":   jobRequest.Payload,
+
+    if err := js.saveJob(c.Request.Context(), &job); err != nil {
+        c.JSON(500, gin.H{"error": "Failed to store job"})
+        return
     }
-    
-    payloadJSON, _ := json.Marshal(jobPayload)
-    
-    err := js.redis.LPush(c.Request.Context(), queueName, payloadJSON).Err()
-    if err != nil {
+
+    // Queue job based on type and priority; workers pull with BRPOPLPUSH so a
+    // job is never lost between dequeue and processing (see worker.go).
+    queueName := js.getQueueForJob(jobRequest.Type, jobRequest.Priority)
+
+    if err := js.enqueue(c.Request.Context(), queueName, jobID); err != nil {
         c.JSON(500, gin.H{"error": "Failed to queue job"})
         return
     }
-    
+
+    js.publishEvent(c.Request.Context(), events.TypeJobQueued, &job)
+
     c.JSON(202, gin.H{
-        "job_id": jobID,
-        "status": "queued",
-        "message": fmt.Sprintf("Job queued in %s", queueName)
+        "job_id":  jobID,
+        "status":  JobStatusQueued,
+        "message": fmt.Sprintf("Job queued in %s", queueName),
     })
 }
 
+// enqueue pushes a job ID onto the named queue. Queues carry IDs, not full
+// payloads, so retries and the janitor can re-read the current job state
+// from its hash instead of replaying a stale snapshot.
+func (js *JobService) enqueue(ctx context.Context, queueName, jobID string) error {
+    return js.redis.LPush(ctx, queueName, jobID).Err()
+}
+
+func (js *JobService) loadJob(ctx context.Context, jobID string) (*Job, error) {
+    jobData, err := js.redis.Get(ctx, jobKey(jobID)).Result()
+    if err != nil {
+        return nil, err
+    }
+
+    var job Job
+    if err := json.Unmarshal([]byte(jobData), &job); err != nil {
+        return nil, err
+    }
+    return &job, nil
+}
+
 func (js *JobService) getJobStatus(c *gin.Context) {
     tenantID := c.GetString("tenant_id")
     jobID := c.Param("job_id")
-    
-    // Get job data
-    jobData, err := js.redis.Get(c.Request.Context(), 
-        fmt.Sprintf("job:%s", jobID)).Result()
+
+    job, err := js.loadJob(c.Request.Context(), jobID)
     if err == redis.Nil {
         c.JSON(404, gin.H{"error": "Job not found"})
         return
+    } else if err != nil {
+        c.JSON(500, gin.H{"error": "Failed to fetch job"})
+        return
     }
-    
-    var job Job
-    json.Unmarshal([]byte(jobData), &job)
-    
+
     // Verify tenant access
     if job.TenantID != tenantID {
         c.JSON(403, gin.H{"error": "Access denied"})
         return
     }
-    
+
     c.JSON(200, job)
 }
 
+// listTenantJobs paginates the tenant's jobs via the tenant:*:jobs ZSET
+// instead of scanning the keyspace with KEYS job:* (which is O(N) and blocks
+// Redis). Results are ordered newest-first; pass "before" (a CreatedAt unix
+// timestamp, exclusive) to fetch the next page.
 func (js *JobService) listTenantJobs(c *gin.Context) {
     tenantID := c.GetString("tenant_id")
-    
-    // Get all job keys for tenant
-    pattern := fmt.Sprintf("job:*")
-    keys, err := js.redis.Keys(c.Request.Context(), pattern).Result()
+    ctx := c.Request.Context()
+
+    limit := 50
+    if v := c.Query("limit"); v != "" {
+        if parsed, err := strconv.Atoi(v); err == nil && parsed > 0 && parsed <= 200 {
+            limit = parsed
+        }
+    }
+
+    max := "+inf"
+    if v := c.Query("before"); v != "" {
+        max = fmt.Sprintf("(%s", v)
+    }
+
+    ids, err := js.redis.ZRevRangeByScore(ctx, tenantJobsKey(tenantID), &redis.ZRangeBy{
+        Min:    "-inf",
+        Max:    max,
+        Offset: 0,
+        Count:  int64(limit),
+    }).Result()
     if err != nil {
         c.JSON(500, gin.H{"error": "Failed to fetch jobs"})
         return
     }
-    
-    var jobs []Job
-    for _, key := range keys {
-        jobData, err := js.redis.Get(c.Request.Context(), key).Result()
+
+    jobs := make([]Job, 0, len(ids))
+    for _, id := range ids {
+        job, err := js.loadJob(ctx, id)
         if err != nil {
+            // Index and hash can drift if a job's TTL expired; skip rather
+            // than fail the whole page.
             continue
         }
-        
-        var job Job
-        if err := json.Unmarshal([]byte(jobData), &job); err != nil {
-            continue
-        }
-        
-        // Filter by tenant
-        if job.TenantID == tenantID {
-            jobs = append(jobs, job)
-        }
+        jobs = append(jobs, *job)
     }
-    
+
+    var nextBefore int64
+    if len(jobs) > 0 {
+        nextBefore = jobs[len(jobs)-1].CreatedAt.Unix()
+    }
+
     c.JSON(200, gin.H{
-        "jobs": jobs,
-        "total": len(jobs)
+        "jobs":        jobs,
+        "total":       len(jobs),
+        "next_before": nextBefore,
     })
+}
+
+// retryJob re-queues a failed or dead-lettered job, resetting its retry
+// count. Used for operator-triggered rejudge via POST /jobs/:id/retry.
+func (js *JobService) retryJob(c *gin.Context) {
+    tenantID := c.GetString("tenant_id")
+    jobID := c.Param("job_id")
+    ctx := c.Request.Context()
+
+    job, err := js.loadJob(ctx, jobID)
+    if err == redis.Nil {
+        c.JSON(404, gin.H{"error": "Job not found"})
+        return
+    } else if err != nil {
+        c.JSON(500, gin.H{"error": "Failed to fetch job"})
+        return
+    }
+
+    if job.TenantID != tenantID {
+        c.JSON(403, gin.H{"error": "Access denied"})
+        return
+    }
+
+    if job.Status != JobStatusFailed && job.Status != JobStatusDeadLetter {
+        c.JSON(409, gin.H{"error": "Only failed or dead-lettered jobs can be retried"})
+        return
+    }
+
+    now := time.Now()
+    job.Status = JobStatusQueued
+    job.RetryCount = 0
+    job.RetriedAt = &now
+    job.Error = ""
+
+    if err := js.saveJob(ctx, job); err != nil {
+        c.JSON(500, gin.H{"error": "Failed to update job"})
+        return
+    }
+
+    queueName := js.getQueueForJob(job.Type, job.Priority)
+    if err := js.enqueue(ctx, queueName, job.ID); err != nil {
+        c.JSON(500, gin.H{"error": "Failed to queue job"})
+        return
+    }
+
+    js.publishEvent(ctx, events.TypeJobQueued, job)
+
+    c.JSON(202, gin.H{"job_id": job.ID, "status": job.Status})
+}
+
+// cancelJob marks a queued job as cancelled. Workers check the job's status
+// after BRPOPLPUSH and skip execution if it's "cancelled", so an
+// in-flight dequeue can still be cancelled right up until the handler runs.
+func (js *JobService) cancelJob(c *gin.Context) {
+    tenantID := c.GetString("tenant_id")
+    jobID := c.Param("job_id")
+    ctx := c.Request.Context()
+
+    job, err := js.loadJob(ctx, jobID)
+    if err == redis.Nil {
+        c.JSON(404, gin.H{"error": "Job not found"})
+        return
+    } else if err != nil {
+        c.JSON(500, gin.H{"error": "Failed to fetch job"})
+        return
+    }
+
+    if job.TenantID != tenantID {
+        c.JSON(403, gin.H{"error": "Access denied"})
+        return
+    }
+
+    if job.Status != JobStatusQueued {
+        c.JSON(409, gin.H{"error": "Only queued jobs can be cancelled"})
+        return
+    }
+
+    job.Status = JobStatusCancelled
+    if err := js.saveJob(ctx, job); err != nil {
+        c.JSON(500, gin.H{"error": "Failed to update job"})
+        return
+    }
+
+    c.JSON(200, gin.H{"job_id": job.ID, "status": job.Status})
+}
+
+// completeJobCallback is called by out-of-process workers that don't run
+// inside this service's own Worker loop (e.g. services/renderer) to report
+// a job's outcome. It's mounted under an internal-only route group, not
+// behind tenant auth, since only other services call it.
+func (js *JobService) completeJobCallback(c *gin.Context) {
+    jobID := c.Param("job_id")
+    ctx := c.Request.Context()
+
+    var body struct {
+        Status string                 `json:"status" binding:"required,oneof=completed failed"`
+        Result map[string]interface{} `json:"result,omitempty"`
+        Error  string                 `json:"error,omitempty"`
+    }
+    if err := c.ShouldBindJSON(&body); err != nil {
+        c.JSON(400, gin.H{"error": err.Error()})
+        return
+    }
+
+    job, err := js.loadJob(ctx, jobID)
+    if err == redis.Nil {
+        c.JSON(404, gin.H{"error": "Job not found"})
+        return
+    } else if err != nil {
+        c.JSON(500, gin.H{"error": "Failed to fetch job"})
+        return
+    }
+
+    if body.Status == JobStatusFailed {
+        // Route through the same retry/DLQ decision the in-process Worker
+        // uses, so a sandboxed render that OOMs or hits its cgroup time
+        // limit gets automatic backoff/retry instead of landing straight
+        // on a terminal status that only a human can recover from.
+        if err := js.failJob(ctx, job, body.Error); err != nil {
+            c.JSON(500, gin.H{"error": "Failed to update job"})
+            return
+        }
+        c.JSON(200, gin.H{"job_id": job.ID, "status": job.Status})
+        return
+    }
+
+    now := time.Now()
+    job.Status = JobStatusCompleted
+    job.Result = body.Result
+    job.CompletedAt = &now
+
+    if err := js.saveJob(ctx, job); err != nil {
+        c.JSON(500, gin.H{"error": "Failed to update job"})
+        return
+    }
+    js.publishEvent(ctx, events.TypeJobCompleted, job)
+
+    c.JSON(200, gin.H{"job_id": job.ID, "status": job.Status})
 }
\ No newline at end of file
@@ -0,0 +1,102 @@
+// services/jobs/retry.go
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "math"
+    "math/rand"
+    "time"
+
+    "github.com/go-redis/redis/v8"
+
+    "github.com/NandoSerrano85/Printer-Saas/backend/internal/events"
+)
+
+// retryScheduleKey is a ZSET of job IDs due for requeue, scored by the unix
+// millisecond timestamp they become due. Scheduling retries here (instead of
+// an in-process timer) means a scheduled retry survives a worker restart:
+// any process's Janitor can promote a due job back onto its queue.
+const retryScheduleKey = "queue:retry"
+
+const (
+    defaultRetryBaseBackoff = time.Second
+    defaultRetryMaxBackoff  = 5 * time.Minute
+)
+
+// computeBackoff returns a jittered exponential delay for retry attempt n
+// (1-indexed): base * 2^(attempt-1), capped at max, +/-20% jitter to avoid
+// thundering-herd retries.
+func computeBackoff(attempt int, base, max time.Duration) time.Duration {
+    d := float64(base) * math.Pow(2, float64(attempt-1))
+    if d > float64(max) {
+        d = float64(max)
+    }
+    jitter := 1 + (rand.Float64()*0.4 - 0.2)
+    return time.Duration(d * jitter)
+}
+
+// failJob is the single retry/DLQ decision point shared by the in-process
+// Worker and the HTTP completion callback used by out-of-process workers
+// like services/renderer, so both paths get the same backoff/DLQ behavior.
+func (js *JobService) failJob(ctx context.Context, job *Job, cause string) error {
+    job.RetryCount++
+    job.Error = cause
+    now := time.Now()
+    job.RetriedAt = &now
+
+    if job.RetryCount > job.MaxRetry {
+        job.Status = JobStatusDeadLetter
+        if err := js.saveJob(ctx, job); err != nil {
+            return err
+        }
+        jobData, _ := json.Marshal(job)
+        js.redis.LPush(ctx, deadLetterKey, jobData)
+        js.publishEvent(ctx, events.TypeJobFailed, job)
+        return nil
+    }
+
+    job.Status = JobStatusFailed
+    if err := js.saveJob(ctx, job); err != nil {
+        return err
+    }
+    js.publishEvent(ctx, events.TypeJobFailed, job)
+
+    delay := computeBackoff(job.RetryCount, defaultRetryBaseBackoff, defaultRetryMaxBackoff)
+    dueAt := now.Add(delay).UnixMilli()
+    return js.redis.ZAdd(ctx, retryScheduleKey, &redis.Z{Score: float64(dueAt), Member: job.ID}).Err()
+}
+
+// promoteDueRetries moves every job whose scheduled retry time has passed
+// back onto its original queue. Called periodically by Janitor so a retry
+// survives the worker process that scheduled it dying before it came due.
+func (js *JobService) promoteDueRetries(ctx context.Context) {
+    now := float64(time.Now().UnixMilli())
+
+    dueIDs, err := js.redis.ZRangeByScore(ctx, retryScheduleKey, &redis.ZRangeBy{
+        Min: "-inf",
+        Max: fmt.Sprintf("%f", now),
+    }).Result()
+    if err != nil {
+        return
+    }
+
+    for _, jobID := range dueIDs {
+        job, err := js.loadJob(ctx, jobID)
+        if err != nil {
+            js.redis.ZRem(ctx, retryScheduleKey, jobID)
+            continue
+        }
+
+        if job.Status == JobStatusFailed {
+            job.Status = JobStatusQueued
+            if err := js.saveJob(ctx, job); err == nil {
+                queue := js.getQueueForJob(job.Type, job.Priority)
+                js.enqueue(ctx, queue, job.ID)
+            }
+        }
+
+        js.redis.ZRem(ctx, retryScheduleKey, jobID)
+    }
+}
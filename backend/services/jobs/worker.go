@@ -0,0 +1,318 @@
+// services/jobs/worker.go
+package main
+
+import (
+    "context"
+    "fmt"
+    "log"
+    "sync"
+    "time"
+
+    "github.com/go-redis/redis/v8"
+
+    "github.com/NandoSerrano85/Printer-Saas/backend/internal/events"
+)
+
+// Handler processes a single job. Returning an error marks the job for
+// retry (or the dead-letter queue once MaxRetry is exhausted).
+type Handler func(ctx context.Context, job *Job) error
+
+// deadLetterKey is the list jobs land on once retries are exhausted.
+const deadLetterKey = "queue:dead_letter"
+
+// inflightKey is the per-worker reliable-queue list used with BRPOPLPUSH.
+// A janitor scans these for jobs whose owning worker died before acking.
+func inflightKey(workerID string) string {
+    return fmt.Sprintf("queue:inflight:%s", workerID)
+}
+
+// WorkerConfig controls concurrency and how often each priority queue is
+// polled relative to the others.
+type WorkerConfig struct {
+    ID          string
+    Concurrency int
+    // QueueWeights maps queue name to how many of every polling round should
+    // be spent on it, e.g. {"jobs:high": 5, "jobs:normal": 3, "jobs:low": 1}.
+    QueueWeights map[string]int
+    PopTimeout   time.Duration
+
+    // LeaseDuration is how long a Running job's lease is valid for before
+    // Janitor.reclaim considers its worker dead. HeartbeatInterval controls
+    // how often process() refreshes the lease while the handler is still
+    // running; it must stay comfortably under LeaseDuration so a live
+    // worker's renewal always lands before the previous lease expires.
+    LeaseDuration     time.Duration
+    HeartbeatInterval time.Duration
+}
+
+func DefaultWorkerConfig(id string) WorkerConfig {
+    return WorkerConfig{
+        ID:          id,
+        Concurrency: 10,
+        QueueWeights: map[string]int{
+            "jobs:high":   5,
+            "jobs:normal": 3,
+            "jobs:low":    1,
+        },
+        PopTimeout:        5 * time.Second,
+        LeaseDuration:     30 * time.Second,
+        HeartbeatInterval: 10 * time.Second,
+    }
+}
+
+// Worker pulls job IDs off the weighted priority queues and dispatches them
+// to the handler registered for the job's Type, modeled on hibiken/asynq.
+type Worker struct {
+    js       *JobService
+    cfg      WorkerConfig
+    handlers map[string]Handler
+    queues   []string // weighted, precomputed from cfg.QueueWeights
+}
+
+func NewWorker(js *JobService, cfg WorkerConfig) *Worker {
+    w := &Worker{
+        js:       js,
+        cfg:      cfg,
+        handlers: make(map[string]Handler),
+    }
+    w.queues = weightedQueueOrder(cfg.QueueWeights)
+    return w
+}
+
+// queueCursor is a rotating index into a weighted queue order, shared
+// across poll() calls on the same goroutine so each call picks up where the
+// last one left off instead of always starting from the front of the list
+// (which would make only the first occurrence of each queue name reachable).
+type queueCursor struct {
+    queues []string
+    pos    int
+}
+
+func (c *queueCursor) next() string {
+    q := c.queues[c.pos%len(c.queues)]
+    c.pos++
+    return q
+}
+
+// weightedQueueOrder expands weights into a round-robin polling order, e.g.
+// {high:2, low:1} -> [high, low, high].
+func weightedQueueOrder(weights map[string]int) []string {
+    total := 0
+    for _, w := range weights {
+        total += w
+    }
+    order := make([]string, 0, total)
+    remaining := make(map[string]int, len(weights))
+    for q, w := range weights {
+        remaining[q] = w
+    }
+    for len(order) < total {
+        for _, q := range sortedKeys(weights) {
+            if remaining[q] > 0 {
+                order = append(order, q)
+                remaining[q]--
+            }
+        }
+    }
+    return order
+}
+
+func sortedKeys(m map[string]int) []string {
+    keys := make([]string, 0, len(m))
+    for k := range m {
+        keys = append(keys, k)
+    }
+    // Stable, deterministic ordering regardless of map iteration.
+    for i := 1; i < len(keys); i++ {
+        for j := i; j > 0 && keys[j] < keys[j-1]; j-- {
+            keys[j], keys[j-1] = keys[j-1], keys[j]
+        }
+    }
+    return keys
+}
+
+// RegisterHandler binds a handler to a job Type. A dequeued job whose Type
+// has no handler is logged and dropped from the queue with its status left
+// "queued"; registering a handler afterwards does not re-queue it.
+func (w *Worker) RegisterHandler(jobType string, h Handler) {
+    w.handlers[jobType] = h
+}
+
+// Run starts cfg.Concurrency goroutines pulling from the weighted queues
+// until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+    var wg sync.WaitGroup
+    for i := 0; i < w.cfg.Concurrency; i++ {
+        wg.Add(1)
+        go func(slot int) {
+            defer wg.Done()
+            inflight := inflightKey(fmt.Sprintf("%s:%d", w.cfg.ID, slot))
+            cursor := &queueCursor{queues: w.queues}
+            for {
+                select {
+                case <-ctx.Done():
+                    return
+                default:
+                }
+                w.poll(ctx, inflight, cursor)
+            }
+        }(i)
+    }
+    wg.Wait()
+}
+
+// poll makes a single BRPOPLPUSH attempt against the next queue in cursor's
+// rotation, moving a job into this worker's in-flight list before
+// processing it. The in-flight entry is only removed after the handler
+// returns, so a janitor can reclaim the job if this process dies mid-handler.
+//
+// Each call advances the cursor by exactly one queue rather than rescanning
+// the whole weighted order, so a single call blocks for at most PopTimeout
+// (not len(w.queues)*PopTimeout) and repeated calls from Run's loop are what
+// give higher-weight queues more turns over time.
+func (w *Worker) poll(ctx context.Context, inflight string, cursor *queueCursor) {
+    queue := cursor.next()
+
+    jobID, err := w.js.redis.BRPopLPush(ctx, queue, inflight, w.cfg.PopTimeout).Result()
+    if err == redis.Nil {
+        return
+    }
+    if err != nil {
+        log.Printf("worker: BRPOPLPUSH %s failed: %v", queue, err)
+        return
+    }
+
+    w.process(ctx, jobID)
+    w.js.redis.LRem(ctx, inflight, 1, jobID)
+}
+
+func (w *Worker) process(ctx context.Context, jobID string) {
+    job, err := w.js.loadJob(ctx, jobID)
+    if err != nil {
+        log.Printf("worker: failed to load job %s: %v", jobID, err)
+        return
+    }
+
+    if job.Status == JobStatusCancelled {
+        return
+    }
+
+    handler, ok := w.handlers[job.Type]
+    if !ok {
+        log.Printf("worker: no handler registered for job type %q", job.Type)
+        return
+    }
+
+    job.Status = JobStatusRunning
+    lease := time.Now().Add(w.cfg.LeaseDuration)
+    job.LeaseExpiresAt = &lease
+    _ = w.js.saveJob(ctx, job)
+    w.js.publishEvent(ctx, events.TypeJobStarted, job)
+
+    heartbeatDone := make(chan struct{})
+    go w.heartbeat(ctx, job.ID, heartbeatDone)
+    defer close(heartbeatDone)
+
+    if err := handler(ctx, job); err != nil {
+        // Shared with the HTTP completion callback used by out-of-process
+        // workers (services/renderer) so both paths get the same
+        // backoff/DLQ behavior; see retry.go.
+        _ = w.js.failJob(ctx, job, err.Error())
+        return
+    }
+
+    now := time.Now()
+    job.Status = JobStatusCompleted
+    job.CompletedAt = &now
+    job.LeaseExpiresAt = nil
+    _ = w.js.saveJob(ctx, job)
+    w.js.publishEvent(ctx, events.TypeJobCompleted, job)
+}
+
+// heartbeat extends jobID's lease every HeartbeatInterval for as long as its
+// handler keeps running, so Janitor.reclaim only re-queues jobs whose worker
+// actually stopped renewing (died, was killed, etc.), not every job that
+// happens to still be in progress.
+func (w *Worker) heartbeat(ctx context.Context, jobID string, done <-chan struct{}) {
+    ticker := time.NewTicker(w.cfg.HeartbeatInterval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-done:
+            return
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            job, err := w.js.loadJob(ctx, jobID)
+            if err != nil || job.Status != JobStatusRunning {
+                return
+            }
+            lease := time.Now().Add(w.cfg.LeaseDuration)
+            job.LeaseExpiresAt = &lease
+            _ = w.js.saveJob(ctx, job)
+        }
+    }
+}
+
+// Janitor periodically reclaims jobs left in a worker's in-flight list
+// because the worker process died before acking (LREM-ing) them.
+type Janitor struct {
+    js       *JobService
+    interval time.Duration
+}
+
+func NewJanitor(js *JobService, interval time.Duration) *Janitor {
+    return &Janitor{js: js, interval: interval}
+}
+
+// Run scans known in-flight lists and pushes any stranded job IDs back onto
+// their original queue, and promotes any durably-scheduled retries (see
+// retry.go) whose backoff has elapsed. It takes the worker slots' in-flight
+// list names from inflightLists rather than scanning for them, since
+// in-flight lists are few and short-lived
+// compared to the job keyspace that listTenantJobs had to stop scanning.
+func (j *Janitor) Run(ctx context.Context, inflightLists []string) {
+    ticker := time.NewTicker(j.interval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            j.reclaim(ctx, inflightLists)
+            j.js.promoteDueRetries(ctx)
+        }
+    }
+}
+
+func (j *Janitor) reclaim(ctx context.Context, inflightLists []string) {
+    now := time.Now()
+    for _, list := range inflightLists {
+        jobIDs, err := j.js.redis.LRange(ctx, list, 0, -1).Result()
+        if err != nil {
+            continue
+        }
+        for _, jobID := range jobIDs {
+            job, err := j.js.loadJob(ctx, jobID)
+            if err != nil || job.Status != JobStatusRunning {
+                continue
+            }
+            // A live worker's heartbeat keeps renewing this; only reclaim
+            // once the lease has actually lapsed, or a second worker can
+            // end up executing the same job concurrently with the first.
+            if job.LeaseExpiresAt == nil || job.LeaseExpiresAt.After(now) {
+                continue
+            }
+            queue := j.js.getQueueForJob(job.Type, job.Priority)
+            if err := j.js.enqueue(ctx, queue, jobID); err != nil {
+                continue
+            }
+            job.Status = JobStatusQueued
+            job.LeaseExpiresAt = nil
+            _ = j.js.saveJob(ctx, job)
+            j.js.redis.LRem(ctx, list, 1, jobID)
+        }
+    }
+}
@@ -2,26 +2,151 @@
 package main
 
 import (
+    "context"
     "encoding/json"
+    "fmt"
     "log"
     "net/http"
-    
+    "sync"
+    "time"
+
     "github.com/gin-gonic/gin"
     "github.com/gorilla/websocket"
+    "github.com/prometheus/client_golang/prometheus"
+
+    "github.com/NandoSerrano85/Printer-Saas/backend/internal/events"
 )
 
+// eventConsumerGroupPrefix namespaces each instance's own Redis Streams
+// consumer group (see ConsumeEvents). A Redis Streams group load-balances
+// each stream entry to exactly one member, so sharing a single group name
+// across replicas of this service would mean any given replica only sees a
+// fraction of events — and could get one for a tenant whose connections it
+// doesn't hold, silently dropping that notification. Giving every replica
+// its own group (each with a single consumer: itself) makes every replica
+// see every event, which is the fan-out this in-process hub actually needs.
+const eventConsumerGroupPrefix = "notifications"
+
+// Hub tuning. pingPeriod must stay well under pongWait so a missed pong is
+// detected before the peer is considered dead.
+const (
+    writeWait  = 10 * time.Second
+    pongWait   = 60 * time.Second
+    pingPeriod = 54 * time.Second
+
+    defaultMaxConnsPerTenant = 100
+)
+
+var liveConnections = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+    Name: "notifications_live_connections",
+    Help: "Current number of open WebSocket connections, by tenant.",
+}, []string{"tenant_id"})
+
+var droppedMessages = prometheus.NewCounterVec(prometheus.CounterOpts{
+    Name: "notifications_dropped_messages_total",
+    Help: "Messages dropped from a client's send queue because it was full.",
+}, []string{"tenant_id"})
+
+func init() {
+    prometheus.MustRegister(liveConnections, droppedMessages)
+}
+
 type NotificationService struct {
-    clients    map[string]map[*websocket.Conn]bool // tenant_id -> connections
-    broadcast  chan NotificationMessage
-    register   chan *Client
-    unregister chan *Client
-    router     *gin.Engine
+    clients          map[string]map[*Client]bool // tenant_id -> connections
+    broadcast        chan NotificationMessage
+    register         chan *Client
+    unregister       chan *Client
+    router           *gin.Engine
+    eventBus         events.Bus
+    maxConnsPerTenant int
+}
+
+func NewNotificationService(eventBus events.Bus) *NotificationService {
+    return &NotificationService{
+        clients:           make(map[string]map[*Client]bool),
+        broadcast:          make(chan NotificationMessage, 256),
+        register:          make(chan *Client),
+        unregister:        make(chan *Client),
+        eventBus:          eventBus,
+        maxConnsPerTenant: defaultMaxConnsPerTenant,
+    }
+}
+
+// ConsumeEvents subscribes to the shared event bus and turns job lifecycle
+// events into NotificationMessages, replacing the old tight coupling where
+// sendJobCompletion could only be called in-process by JobService.
+//
+// consumerName must be unique per running instance (e.g. pod name): it both
+// identifies this consumer within its group and, combined with
+// eventConsumerGroupPrefix, names that group, so each instance gets its own
+// group and sees every event rather than load-balancing the stream with
+// sibling replicas.
+func (ns *NotificationService) ConsumeEvents(ctx context.Context, consumerName string) error {
+    group := fmt.Sprintf("%s:%s", eventConsumerGroupPrefix, consumerName)
+    return ns.eventBus.Subscribe(ctx, group, consumerName, func(ctx context.Context, evt events.Event) error {
+        msg, ok := notificationForEvent(evt)
+        if !ok {
+            return nil
+        }
+        ns.broadcast <- msg
+        return nil
+    })
+}
+
+func notificationForEvent(evt events.Event) (NotificationMessage, bool) {
+    titles := map[string]string{
+        events.TypeJobQueued:    "Job Queued",
+        events.TypeJobStarted:   "Job Started",
+        events.TypeJobCompleted: "Job Completed",
+        events.TypeJobFailed:    "Job Failed",
+    }
+    title, ok := titles[evt.Type]
+    if !ok {
+        return NotificationMessage{}, false
+    }
+
+    return NotificationMessage{
+        TenantID:  evt.TenantID,
+        Type:      evt.Type,
+        Title:     title,
+        Message:   fmt.Sprintf("%v", evt.Payload["type"]),
+        Data:      evt.Payload,
+        Timestamp: evt.Timestamp.Unix(),
+    }, true
 }
 
 type Client struct {
     TenantID string
     Conn     *websocket.Conn
     Send     chan NotificationMessage
+
+    // accepted is signaled by run() once it has decided whether to admit
+    // this client, so handleWebSocket never touches Send (for backlog
+    // replay) or starts the pumps until it knows run() isn't about to
+    // close(Send) itself in the connection-cap-rejection branch.
+    accepted chan bool
+
+    topicsMu sync.RWMutex
+    topics   map[string]bool
+}
+
+// controlMessage is a client->server frame sent over the same WebSocket
+// connection: subscribe to topics, or ack a delivered message ID.
+type controlMessage struct {
+    Action    string   `json:"action"` // "subscribe" or "ack"
+    Topics    []string `json:"topics,omitempty"`
+    MessageID string   `json:"message_id,omitempty"`
+}
+
+func (c *Client) subscribe(topics []string) {
+    c.topicsMu.Lock()
+    defer c.topicsMu.Unlock()
+    if c.topics == nil {
+        c.topics = make(map[string]bool)
+    }
+    for _, t := range topics {
+        c.topics[t] = true
+    }
 }
 
 type NotificationMessage struct {
@@ -41,40 +166,114 @@ var upgrader = websocket.Upgrader{
 
 func (ns *NotificationService) handleWebSocket(c *gin.Context) {
     tenantID := c.GetString("tenant_id")
-    
+
     conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
     if err != nil {
         log.Printf("WebSocket upgrade failed: %v", err)
         return
     }
-    
+
     client := &Client{
         TenantID: tenantID,
         Conn:     conn,
         Send:     make(chan NotificationMessage, 256),
+        accepted: make(chan bool, 1),
     }
-    
+
     ns.register <- client
-    
+    if !<-client.accepted {
+        // run() has already closed Send and the connection for us.
+        return
+    }
+
+    // A client reconnecting after a drop sends the ID of the last event it
+    // saw; replay anything it missed from the tenant's capped event log.
+    // The client is already registered with the hub at this point, so live
+    // broadcasts arriving meanwhile can be queued among the replayed ones.
+    if lastEventID := c.GetHeader("Last-Event-ID"); lastEventID != "" && ns.eventBus != nil {
+        backlog, err := ns.eventBus.Replay(c.Request.Context(), tenantID, lastEventID)
+        if err != nil {
+            log.Printf("event replay failed for tenant %s: %v", tenantID, err)
+        }
+        for _, evt := range backlog {
+            if msg, ok := notificationForEvent(evt); ok {
+                client.Send <- msg
+            }
+        }
+    }
+
     go client.writePump()
     go client.readPump(ns.unregister)
 }
 
+// writePump owns the connection's writer side: it drains Send, applies a
+// write deadline to every frame, and pings the peer so a half-open
+// connection is detected instead of leaking forever.
 func (c *Client) writePump() {
-    defer c.Conn.Close()
-    
+    ticker := time.NewTicker(pingPeriod)
+    defer func() {
+        ticker.Stop()
+        c.Conn.Close()
+    }()
+
     for {
         select {
         case message, ok := <-c.Send:
+            c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
             if !ok {
                 c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
                 return
             }
-            
+
             if err := c.Conn.WriteJSON(message); err != nil {
                 log.Printf("WebSocket write error: %v", err)
                 return
             }
+
+        case <-ticker.C:
+            c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
+            if err := c.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+                return
+            }
+        }
+    }
+}
+
+// readPump owns the connection's reader side: it enforces the read
+// deadline/pong handler pairing that detects dead peers, and decodes
+// client->server control frames (topic subscriptions, message acks).
+func (c *Client) readPump(unregister chan *Client) {
+    defer func() {
+        unregister <- c
+        c.Conn.Close()
+    }()
+
+    c.Conn.SetReadDeadline(time.Now().Add(pongWait))
+    c.Conn.SetPongHandler(func(string) error {
+        c.Conn.SetReadDeadline(time.Now().Add(pongWait))
+        return nil
+    })
+
+    for {
+        _, data, err := c.Conn.ReadMessage()
+        if err != nil {
+            if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+                log.Printf("WebSocket read error: %v", err)
+            }
+            return
+        }
+
+        var msg controlMessage
+        if err := json.Unmarshal(data, &msg); err != nil {
+            continue
+        }
+
+        switch msg.Action {
+        case "subscribe":
+            c.subscribe(msg.Topics)
+        case "ack":
+            // Acks are currently informational only; nothing server-side is
+            // retried off the back of a missing ack yet.
         }
     }
 }
@@ -84,36 +283,65 @@ func (ns *NotificationService) run() {
         select {
         case client := <-ns.register:
             if ns.clients[client.TenantID] == nil {
-                ns.clients[client.TenantID] = make(map[*websocket.Conn]bool)
+                ns.clients[client.TenantID] = make(map[*Client]bool)
+            }
+
+            if len(ns.clients[client.TenantID]) >= ns.maxConnsPerTenant {
+                log.Printf("tenant %s at connection cap (%d), rejecting new client", client.TenantID, ns.maxConnsPerTenant)
+                close(client.Send)
+                client.Conn.Close()
+                client.accepted <- false
+                continue
             }
-            ns.clients[client.TenantID][client.Conn] = true
+
+            ns.clients[client.TenantID][client] = true
+            liveConnections.WithLabelValues(client.TenantID).Inc()
             log.Printf("Client connected for tenant: %s", client.TenantID)
-            
+            client.accepted <- true
+
         case client := <-ns.unregister:
             if clients, ok := ns.clients[client.TenantID]; ok {
-                if _, ok := clients[client.Conn]; ok {
-                    delete(clients, client.Conn)
+                if _, ok := clients[client]; ok {
+                    delete(clients, client)
                     close(client.Send)
                     client.Conn.Close()
+                    liveConnections.WithLabelValues(client.TenantID).Dec()
                 }
             }
-            
+
         case message := <-ns.broadcast:
             if clients, ok := ns.clients[message.TenantID]; ok {
-                for conn := range clients {
-                    select {
-                    case conn.WriteJSON(message):
-                    default:
-                        close(conn.Send)
-                        delete(clients, conn)
-                        conn.Close()
-                    }
+                for client := range clients {
+                    deliver(client, message)
                 }
             }
         }
     }
 }
 
+// deliver enqueues message on the client's buffered Send channel. If the
+// buffer is full the oldest queued message is dropped to make room, rather
+// than blocking the hub or disconnecting a merely-slow client outright.
+func deliver(client *Client, message NotificationMessage) {
+    select {
+    case client.Send <- message:
+        return
+    default:
+    }
+
+    select {
+    case <-client.Send:
+        droppedMessages.WithLabelValues(client.TenantID).Inc()
+    default:
+    }
+
+    select {
+    case client.Send <- message:
+    default:
+        droppedMessages.WithLabelValues(client.TenantID).Inc()
+    }
+}
+
 // Notification triggers
 func (ns *NotificationService) sendJobCompletion(tenantID, jobID, jobType string, result interface{}) {
     message := NotificationMessage{
@@ -124,6 +352,6 @@ func (ns *NotificationService) sendJobCompletion(tenantID, jobID, jobType string
         Data:      map[string]interface{}{"job_id": jobID, "result": result},
         Timestamp: time.Now().Unix(),
     }
-    
+
     ns.broadcast <- message
-}
\ No newline at end of file
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "log"
+    "net/http"
+    "time"
+)
+
+var callbackClient = &http.Client{Timeout: 10 * time.Second}
+
+// callback reports the render outcome to the jobs service's internal
+// completion endpoint, which updates the job's status and publishes the
+// job.completed/job.failed event NotificationService consumes.
+func (rs *RendererService) callback(ctx context.Context, jobID string, success bool, result map[string]interface{}, errMsg string) {
+    status := JobStatusFailed
+    if success {
+        status = JobStatusCompleted
+    }
+
+    body, err := json.Marshal(map[string]interface{}{
+        "status": status,
+        "result": result,
+        "error":  errMsg,
+    })
+    if err != nil {
+        log.Printf("renderer: failed to marshal callback body for job %s: %v", jobID, err)
+        return
+    }
+
+    url := fmt.Sprintf("%s/internal/jobs/%s/complete", rs.jobsBaseURL, jobID)
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+    if err != nil {
+        log.Printf("renderer: failed to build callback request for job %s: %v", jobID, err)
+        return
+    }
+    req.Header.Set("Content-Type", "application/json")
+
+    resp, err := callbackClient.Do(req)
+    if err != nil {
+        log.Printf("renderer: callback failed for job %s: %v", jobID, err)
+        return
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode >= 300 {
+        log.Printf("renderer: callback for job %s returned status %d", jobID, resp.StatusCode)
+    }
+}
+
+// Mirrors the job status constants in services/jobs/main.go; duplicated
+// here since the two are independent `package main` binaries that can't
+// import each other's types.
+const (
+    JobStatusCompleted = "completed"
+    JobStatusFailed    = "failed"
+)
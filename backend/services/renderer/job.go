@@ -0,0 +1,11 @@
+package main
+
+import "encoding/json"
+
+func parseMockupJob(raw string) (*MockupGenerationJob, error) {
+    var job MockupGenerationJob
+    if err := json.Unmarshal([]byte(raw), &job); err != nil {
+        return nil, err
+    }
+    return &job, nil
+}
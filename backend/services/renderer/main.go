@@ -0,0 +1,91 @@
+// services/renderer/main.go
+package main
+
+import (
+    "context"
+    "fmt"
+    "log"
+    "time"
+
+    "github.com/go-redis/redis/v8"
+    "github.com/minio/minio-go/v7"
+)
+
+// artifactsBucket is where rendered mockups are uploaded, under
+// tenant_id/mockups/<job_id>/.
+const artifactsBucket = "tenant-render-artifacts"
+
+// renderQueue is the queue DesignService.generateMockup pushes
+// MockupGenerationJob payloads onto (services/design's renderQueue matches);
+// RendererService pulls from it directly rather than running inside the
+// generic jobs Worker, since rendering needs its own sandboxed process.
+const renderQueue = "jobs:render"
+
+// MockupGenerationJob is the payload DesignService.generateMockup queues.
+type MockupGenerationJob struct {
+    JobID    string `json:"job_id"`
+    TenantID string `json:"tenant_id"`
+    DesignID string `json:"design_id"`
+    FilePath string `json:"file_path"`
+    Recipe   string `json:"recipe"` // e.g. "t-shirt", "mug", "poster"
+}
+
+// RendererService pulls MockupGenerationJobs off the queue, renders them
+// inside a sandbox bounded by resource limits, uploads the resulting
+// artifacts, and calls back into the jobs service with the outcome. This
+// adapts the woj-runner pattern (separate runner binary, resource-bounded
+// execution, signed results) to mockup rendering. It doesn't publish events
+// itself; the jobs service's completion callback does that once it records
+// the terminal status.
+type RendererService struct {
+    redis       *redis.Client
+    minioClient *minio.Client
+    jobsBaseURL string
+    recipes     map[string]Recipe
+    sandbox     Sandbox
+    signingKey  []byte
+}
+
+func (rs *RendererService) Run(ctx context.Context) {
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        default:
+        }
+
+        payloadJSON, err := rs.redis.BRPop(ctx, 5*time.Second, renderQueue).Result()
+        if err == redis.Nil {
+            continue
+        }
+        if err != nil {
+            log.Printf("renderer: BRPOP failed: %v", err)
+            continue
+        }
+
+        // payloadJSON is [queueName, value]
+        rs.handle(ctx, payloadJSON[1])
+    }
+}
+
+func (rs *RendererService) handle(ctx context.Context, rawJob string) {
+    job, err := parseMockupJob(rawJob)
+    if err != nil {
+        log.Printf("renderer: failed to parse job: %v", err)
+        return
+    }
+
+    recipe, ok := rs.recipes[job.Recipe]
+    if !ok {
+        rs.callback(ctx, job.JobID, false, nil, fmt.Sprintf("unknown recipe %q", job.Recipe))
+        return
+    }
+
+    result, err := rs.render(ctx, job, recipe)
+    if err != nil {
+        rs.callback(ctx, job.JobID, false, nil, err.Error())
+        return
+    }
+
+    rs.callback(ctx, job.JobID, true, result, "")
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+
+    "gopkg.in/yaml.v3"
+)
+
+// Recipe describes a whitelisted render template. Only recipes loaded by
+// LoadRecipes at startup can be rendered; an unrecognized recipe name in a
+// job is rejected rather than rendering arbitrary attacker-controlled
+// templates.
+type Recipe struct {
+    Name          string `yaml:"name"`
+    TemplatePath  string `yaml:"template_path"`
+    OutputWidth   int    `yaml:"output_width"`
+    OutputHeight  int    `yaml:"output_height"`
+    CPULimitCores float64 `yaml:"cpu_limit_cores"`
+    MemoryLimitMB int64   `yaml:"memory_limit_mb"`
+    TimeLimit     string  `yaml:"time_limit"` // parsed with time.ParseDuration
+}
+
+// LoadRecipes reads every *.yaml file in dir into the whitelist, keyed by
+// recipe name. Ships with t-shirt, mug, and poster recipes (see
+// services/renderer/recipes/).
+func LoadRecipes(dir string) (map[string]Recipe, error) {
+    entries, err := os.ReadDir(dir)
+    if err != nil {
+        return nil, fmt.Errorf("renderer: failed to read recipe dir %s: %w", dir, err)
+    }
+
+    recipes := make(map[string]Recipe)
+    for _, entry := range entries {
+        if entry.IsDir() || filepath.Ext(entry.Name()) != ".yaml" {
+            continue
+        }
+
+        data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+        if err != nil {
+            return nil, err
+        }
+
+        var recipe Recipe
+        if err := yaml.Unmarshal(data, &recipe); err != nil {
+            return nil, fmt.Errorf("renderer: invalid recipe %s: %w", entry.Name(), err)
+        }
+        if recipe.Name == "" {
+            return nil, fmt.Errorf("renderer: recipe %s missing name", entry.Name())
+        }
+
+        recipes[recipe.Name] = recipe
+    }
+
+    return recipes, nil
+}
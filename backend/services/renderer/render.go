@@ -0,0 +1,88 @@
+package main
+
+import (
+    "bytes"
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "os"
+    "path/filepath"
+
+    "github.com/minio/minio-go/v7"
+)
+
+// render runs job through the sandbox, uploads the resulting artifacts to
+// tenant_id/mockups/<job_id>/, and returns a signed manifest describing
+// them.
+func (rs *RendererService) render(ctx context.Context, job *MockupGenerationJob, recipe Recipe) (map[string]interface{}, error) {
+    outDir, err := os.MkdirTemp("", "render-"+job.JobID)
+    if err != nil {
+        return nil, fmt.Errorf("render: failed to create output dir: %w", err)
+    }
+    defer os.RemoveAll(outDir)
+
+    if err := rs.sandbox.Run(ctx, job, recipe, outDir); err != nil {
+        return nil, err
+    }
+
+    entries, err := os.ReadDir(outDir)
+    if err != nil {
+        return nil, fmt.Errorf("render: failed to read output dir: %w", err)
+    }
+    if len(entries) == 0 {
+        return nil, fmt.Errorf("render: no artifacts produced")
+    }
+
+    prefix := fmt.Sprintf("%s/mockups/%s", job.TenantID, job.JobID)
+    manifest := Manifest{
+        JobID:     job.JobID,
+        TenantID:  job.TenantID,
+        Recipe:    recipe.Name,
+        Artifacts: make(map[string]string, len(entries)),
+    }
+
+    for _, entry := range entries {
+        if entry.IsDir() {
+            continue
+        }
+
+        localPath := filepath.Join(outDir, entry.Name())
+        data, err := os.ReadFile(localPath)
+        if err != nil {
+            return nil, fmt.Errorf("render: failed to read artifact %s: %w", entry.Name(), err)
+        }
+
+        sum := sha256.Sum256(data)
+        hash := hex.EncodeToString(sum[:])
+
+        key := fmt.Sprintf("%s/%s", prefix, entry.Name())
+        if _, err := rs.minioClient.PutObject(ctx, artifactsBucket, key, bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{}); err != nil {
+            return nil, fmt.Errorf("render: failed to upload artifact %s: %w", entry.Name(), err)
+        }
+
+        manifest.Artifacts[entry.Name()] = hash
+    }
+
+    if err := manifest.sign(rs.signingKey); err != nil {
+        return nil, fmt.Errorf("render: failed to sign manifest: %w", err)
+    }
+
+    manifestJSON, err := json.Marshal(manifest)
+    if err != nil {
+        return nil, err
+    }
+    manifestKey := fmt.Sprintf("%s/manifest.json", prefix)
+    if _, err := rs.minioClient.PutObject(ctx, artifactsBucket, manifestKey, bytes.NewReader(manifestJSON), int64(len(manifestJSON)), minio.PutObjectOptions{
+        ContentType: "application/json",
+    }); err != nil {
+        return nil, fmt.Errorf("render: failed to upload manifest: %w", err)
+    }
+
+    return map[string]interface{}{
+        "manifest_key": manifestKey,
+        "artifacts":    manifest.Artifacts,
+        "signature":    manifest.Signature,
+    }, nil
+}
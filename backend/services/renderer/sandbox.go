@@ -0,0 +1,99 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "strconv"
+    "time"
+)
+
+// Sandbox runs a render command under per-job resource limits.
+type Sandbox interface {
+    // Run executes the recipe's renderer for the given job, writing output
+    // artifacts under outDir. It enforces recipe's CPU/memory/time limits:
+    // CPU use is throttled to the limit, and the process is killed if it
+    // exceeds its memory or time limit.
+    Run(ctx context.Context, job *MockupGenerationJob, recipe Recipe, outDir string) error
+}
+
+// cgroupSandbox bounds each render with a dedicated cgroup v2 so one job
+// can't starve the host of CPU/memory, plus a wall-clock timeout via
+// context cancellation. This requires cgroup v2 mounted at CgroupRoot with
+// delegation to this process (e.g. via systemd's Delegate=yes).
+type cgroupSandbox struct {
+    CgroupRoot string // e.g. "/sys/fs/cgroup/renderer"
+    RendererBin string // path to the render executable invoked per job
+}
+
+func NewCgroupSandbox(cgroupRoot, rendererBin string) Sandbox {
+    return &cgroupSandbox{CgroupRoot: cgroupRoot, RendererBin: rendererBin}
+}
+
+func (s *cgroupSandbox) Run(ctx context.Context, job *MockupGenerationJob, recipe Recipe, outDir string) error {
+    timeLimit, err := time.ParseDuration(recipe.TimeLimit)
+    if err != nil {
+        timeLimit = 30 * time.Second
+    }
+
+    runCtx, cancel := context.WithTimeout(ctx, timeLimit)
+    defer cancel()
+
+    cgroupPath := filepath.Join(s.CgroupRoot, job.JobID)
+    if err := s.createCgroup(cgroupPath, recipe); err != nil {
+        return fmt.Errorf("sandbox: failed to create cgroup: %w", err)
+    }
+    defer os.RemoveAll(cgroupPath)
+
+    cmd := exec.CommandContext(runCtx, s.RendererBin,
+        "--template", recipe.TemplatePath,
+        "--input", job.FilePath,
+        "--out", outDir,
+        "--width", strconv.Itoa(recipe.OutputWidth),
+        "--height", strconv.Itoa(recipe.OutputHeight),
+    )
+
+    if err := cmd.Start(); err != nil {
+        return fmt.Errorf("sandbox: failed to start render process: %w", err)
+    }
+
+    if err := s.addToCgroup(cgroupPath, cmd.Process.Pid); err != nil {
+        cmd.Process.Kill()
+        return fmt.Errorf("sandbox: failed to join cgroup: %w", err)
+    }
+
+    if err := cmd.Wait(); err != nil {
+        if runCtx.Err() == context.DeadlineExceeded {
+            return fmt.Errorf("sandbox: render exceeded time limit of %s", timeLimit)
+        }
+        return fmt.Errorf("sandbox: render process failed: %w", err)
+    }
+
+    return nil
+}
+
+// createCgroup writes cpu.max and memory.max for a per-job cgroup v2
+// directory, derived from the recipe's resource limits.
+func (s *cgroupSandbox) createCgroup(path string, recipe Recipe) error {
+    if err := os.MkdirAll(path, 0o755); err != nil {
+        return err
+    }
+
+    cpuQuotaUS := int64(recipe.CPULimitCores * 100000)
+    if err := os.WriteFile(filepath.Join(path, "cpu.max"), []byte(fmt.Sprintf("%d 100000", cpuQuotaUS)), 0o644); err != nil {
+        return err
+    }
+
+    memLimitBytes := recipe.MemoryLimitMB * 1024 * 1024
+    if err := os.WriteFile(filepath.Join(path, "memory.max"), []byte(strconv.FormatInt(memLimitBytes, 10)), 0o644); err != nil {
+        return err
+    }
+
+    return nil
+}
+
+func (s *cgroupSandbox) addToCgroup(path string, pid int) error {
+    return os.WriteFile(filepath.Join(path, "cgroup.procs"), []byte(strconv.Itoa(pid)), 0o644)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+)
+
+// Manifest lists the artifacts produced for a mockup job, signed so the
+// frontend can verify they weren't swapped for something else in storage
+// between render time and download time.
+type Manifest struct {
+    JobID     string            `json:"job_id"`
+    TenantID  string            `json:"tenant_id"`
+    Recipe    string            `json:"recipe"`
+    Artifacts map[string]string `json:"artifacts"` // relative path -> sha256
+    Signature string            `json:"signature,omitempty"`
+}
+
+// sign computes an HMAC-SHA256 over the manifest's canonical JSON (with
+// Signature left empty) and sets Signature to the hex-encoded result.
+func (m *Manifest) sign(key []byte) error {
+    m.Signature = ""
+    payload, err := json.Marshal(m)
+    if err != nil {
+        return err
+    }
+
+    mac := hmac.New(sha256.New, key)
+    mac.Write(payload)
+    m.Signature = hex.EncodeToString(mac.Sum(nil))
+    return nil
+}
+
+// Verify reports whether the manifest's signature matches its content,
+// for the frontend (or any downstream consumer holding the shared key) to
+// confirm artifacts weren't swapped after rendering.
+func (m *Manifest) Verify(key []byte) bool {
+    got := m.Signature
+    copy := *m
+    if err := copy.sign(key); err != nil {
+        return false
+    }
+    return hmac.Equal([]byte(got), []byte(copy.Signature))
+}